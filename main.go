@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shilan42/CafeFinder/middleware"
+)
+
+// Cafe описывает одно заведение: имя, город, адрес и координаты,
+// используемые для геопривязанных форматов ответа.
+type Cafe struct {
+	Name    string
+	City    string
+	Address string
+	Lat     float64
+	Lon     float64
+	Tags    string
+}
+
+// cafeList — заглушка вместо базы данных: кафе, сгруппированные по городу.
+var cafeList = map[string][]Cafe{
+	"moscow": {
+		{Name: "Мир кофе", City: "moscow", Address: "Арбат, 10", Lat: 55.751999, Lon: 37.600002},
+		{Name: "Кофе и кофе", City: "moscow", Address: "Тверская, 5", Lat: 55.764898, Lon: 37.605988},
+		{Name: "Вилка и ложка", City: "moscow", Address: "Пятницкая, 3", Lat: 55.742606, Lon: 37.629417},
+		{Name: "Сладкоежка", City: "moscow", Address: "Ленинский проспект, 15", Lat: 55.708763, Lon: 37.588314},
+	},
+	"tula": {
+		{Name: "Кофе тут", City: "tula", Address: "Проспект Ленина, 2", Lat: 54.193122, Lon: 37.617348},
+		{Name: "Самый лучший кофе", City: "tula", Address: "Улица Металлургов, 8", Lat: 54.185162, Lon: 37.650999},
+		{Name: "Вилка-минутка", City: "tula", Address: "Советская улица, 11", Lat: 54.200021, Lon: 37.601233},
+		{Name: "Пышки", City: "tula", Address: "Улица Жуковского, 1", Lat: 54.178879, Lon: 37.622743},
+	},
+}
+
+// cafeJSON — представление кафе для форматов application/json и application/geo+json.
+type cafeJSON struct {
+	Name    string  `json:"name"`
+	City    string  `json:"city"`
+	Address string  `json:"address"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// geoFeature и geoFeatureCollection реализуют минимально достаточный GeoJSON
+// (RFC 7946) для отображения результатов на карте.
+type geoGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoFeature struct {
+	Type       string      `json:"type"`
+	Geometry   geoGeometry `json:"geometry"`
+	Properties cafeJSON    `json:"properties"`
+}
+
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+// defaultStore — Store поверх исходной заглушки cafeList, используемый
+// сервером и существующими тестами, если явно не передан другой Store.
+var defaultStore Store = NewMemoryStore(cafeList)
+
+// mainHandle — обработчик /cafe поверх defaultStore, сохранённый как
+// package-level функция ради обратной совместимости со старым кодом и тестами.
+var mainHandle = newHandler(defaultStore)
+
+/*
+newHandler:
+Назначение: фабрика обработчика /cafe
+Описание: возвращает http.HandlerFunc, читающий кафе из переданного Store.
+Поддерживает режим близости (lat/lon/radius_km), поиск (search,
+search_mode=regex, regex=), пагинацию (offset/count) и content negotiation
+по формату ответа.
+*/
+func newHandler(s Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if _, hasLat := req.URL.Query()["lat"]; hasLat {
+			proximityHandle(s, w, req)
+			return
+		}
+
+		city := req.URL.Query().Get("city")
+		cafes, err := s.List(city)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		countStr := req.URL.Query().Get("count")
+		count := len(cafes)
+		if countStr != "" {
+			c, err := strconv.Atoi(countStr)
+			if err != nil || c < 0 {
+				http.Error(w, "incorrect count", http.StatusBadRequest)
+				return
+			}
+			count = c
+		}
+
+		pattern, usingRegex := req.URL.Query()["regex"]
+		switch {
+		case usingRegex:
+			re, err := regexp.Compile(pattern[0])
+			if err != nil {
+				http.Error(w, "invalid regex", http.StatusBadRequest)
+				return
+			}
+			cafes = filterByRegex(cafes, re)
+		case req.URL.Query().Get("search_mode") == "regex":
+			re, err := regexp.Compile(req.URL.Query().Get("search"))
+			if err != nil {
+				http.Error(w, "invalid regex", http.StatusBadRequest)
+				return
+			}
+			cafes = filterByRegex(cafes, re)
+		case req.URL.Query().Get("search") != "":
+			cafes = filterBySubstring(cafes, req.URL.Query().Get("search"))
+		}
+
+		offset := 0
+		if offsetStr := req.URL.Query().Get("offset"); offsetStr != "" {
+			o, err := strconv.Atoi(offsetStr)
+			if err != nil || o < 0 {
+				http.Error(w, "incorrect offset", http.StatusBadRequest)
+				return
+			}
+			offset = o
+		}
+
+		total := len(cafes)
+		start := offset
+		if start > total {
+			start = total
+		}
+		end := start + count
+		if end > total {
+			end = total
+		}
+
+		setPaginationHeaders(w, req, offset, count, total)
+		writeCafes(w, req, cafes[start:end])
+	}
+}
+
+// setPaginationHeaders выставляет X-Total-Count и, если есть предыдущая,
+// следующая или последняя страница, заголовок Link (RFC 5988).
+func setPaginationHeaders(w http.ResponseWriter, req *http.Request, offset, count, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if count <= 0 || total == 0 {
+		return
+	}
+
+	var links []string
+	if offset > 0 {
+		prev := offset - count
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(req, prev)))
+	}
+	if offset+count < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(req, offset+count)))
+	}
+	last := ((total - 1) / count) * count
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(req, last)))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// pageURL возвращает путь и query запроса req с параметром offset,
+// заменённым на newOffset, — для использования в заголовке Link.
+func pageURL(req *http.Request, newOffset int) string {
+	q := req.URL.Query()
+	q.Set("offset", strconv.Itoa(newOffset))
+	u := *req.URL
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// earthRadiusKm — средний радиус Земли, используется в haversineKm.
+const earthRadiusKm = 6371.0
+
+// haversineKm возвращает расстояние по большому кругу между двумя точками
+// (в градусах) в километрах.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return earthRadiusKm * c
+}
+
+/*
+proximityHandle:
+Назначение: геопространственный режим /cafe
+Описание: принимает lat=, lon= и radius_km=, возвращает кафе в радиусе от
+точки, по умолчанию отсортированные по расстоянию (sort=name — по имени).
+Если передан city, поиск ограничивается этим городом, иначе — всеми.
+*/
+func proximityHandle(store Store, w http.ResponseWriter, req *http.Request) {
+	lat, err := strconv.ParseFloat(req.URL.Query().Get("lat"), 64)
+	if err != nil {
+		http.Error(w, "incorrect coordinates", http.StatusBadRequest)
+		return
+	}
+	lon, err := strconv.ParseFloat(req.URL.Query().Get("lon"), 64)
+	if err != nil {
+		http.Error(w, "incorrect coordinates", http.StatusBadRequest)
+		return
+	}
+
+	radiusKm := earthRadiusKm * math.Pi // по умолчанию — весь земной шар
+	if p := req.URL.Query().Get("radius_km"); p != "" {
+		radiusKm, err = strconv.ParseFloat(p, 64)
+		if err != nil {
+			http.Error(w, "incorrect coordinates", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pool []Cafe
+	if city := req.URL.Query().Get("city"); city != "" {
+		cafes, err := store.List(city)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pool = cafes
+	} else {
+		for _, city := range store.Cities() {
+			cafes, err := store.List(city)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			pool = append(pool, cafes...)
+		}
+	}
+
+	type ranked struct {
+		cafe     Cafe
+		distance float64
+	}
+	nearby := make([]ranked, 0, len(pool))
+	for _, c := range pool {
+		d := haversineKm(lat, lon, c.Lat, c.Lon)
+		if d <= radiusKm {
+			nearby = append(nearby, ranked{cafe: c, distance: d})
+		}
+	}
+
+	switch req.URL.Query().Get("sort") {
+	case "name":
+		sort.Slice(nearby, func(i, j int) bool { return nearby[i].cafe.Name < nearby[j].cafe.Name })
+	default:
+		sort.Slice(nearby, func(i, j int) bool { return nearby[i].distance < nearby[j].distance })
+	}
+
+	count := len(nearby)
+	if countStr := req.URL.Query().Get("count"); countStr != "" {
+		c, err := strconv.Atoi(countStr)
+		if err != nil || c < 0 {
+			http.Error(w, "incorrect count", http.StatusBadRequest)
+			return
+		}
+		count = c
+	}
+	if count > len(nearby) {
+		count = len(nearby)
+	}
+
+	cafes := make([]Cafe, count)
+	for i := 0; i < count; i++ {
+		cafes[i] = nearby[i].cafe
+	}
+
+	writeCafes(w, req, cafes)
+}
+
+// filterBySubstring возвращает кафе, в имени которых встречается search
+// (без учёта регистра).
+func filterBySubstring(cafes []Cafe, search string) []Cafe {
+	search = strings.ToLower(search)
+	filtered := make([]Cafe, 0, len(cafes))
+	for _, c := range cafes {
+		if strings.Contains(strings.ToLower(c.Name), search) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// filterByRegex возвращает кафе, имя которых удовлетворяет регулярному выражению re.
+func filterByRegex(cafes []Cafe, re *regexp.Regexp) []Cafe {
+	filtered := make([]Cafe, 0, len(cafes))
+	for _, c := range cafes {
+		if re.MatchString(c.Name) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// responseFormat определяет желаемый формат ответа по query-параметру
+// format= и, если он не задан, по заголовку Accept.
+func responseFormat(req *http.Request) string {
+	switch req.URL.Query().Get("format") {
+	case "json":
+		return "json"
+	case "geojson", "geo+json":
+		return "geojson"
+	}
+
+	accept := req.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/geo+json"):
+		return "geojson"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+// writeCafes сериализует cafes в формате, выбранном responseFormat, и
+// пишет его в w. Формат по умолчанию — список имён через запятую, как и
+// раньше, чтобы не ломать существующих клиентов.
+func writeCafes(w http.ResponseWriter, req *http.Request, cafes []Cafe) {
+	switch responseFormat(req) {
+	case "json":
+		items := make([]cafeJSON, 0, len(cafes))
+		for _, c := range cafes {
+			items = append(items, toCafeJSON(c))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	case "geojson":
+		features := make([]geoFeature, 0, len(cafes))
+		for _, c := range cafes {
+			features = append(features, geoFeature{
+				Type: "Feature",
+				Geometry: geoGeometry{
+					Type:        "Point",
+					Coordinates: []float64{c.Lon, c.Lat},
+				},
+				Properties: toCafeJSON(c),
+			})
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		json.NewEncoder(w).Encode(geoFeatureCollection{Type: "FeatureCollection", Features: features})
+	default:
+		names := make([]string, 0, len(cafes))
+		for _, c := range cafes {
+			names = append(names, c.Name)
+		}
+		w.Write([]byte(strings.Join(names, ",")))
+	}
+}
+
+func toCafeJSON(c Cafe) cafeJSON {
+	return cafeJSON{Name: c.Name, City: c.City, Address: c.Address, Lat: c.Lat, Lon: c.Lon}
+}
+
+func main() {
+	dataPath := flag.String("data", os.Getenv("CAFE_DATA"), "path to a CSV/JSON file with cafe data (defaults to the built-in list)")
+	flag.Parse()
+
+	store := defaultStore
+	if *dataPath != "" {
+		s, err := NewFileStore(*dataPath)
+		if err != nil {
+			log.Fatalf("loading cafe data from %s: %v", *dataPath, err)
+		}
+		store = s
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cafe", newHandler(store))
+	http.ListenAndServe(":8080", middleware.LoggingHandler(mux))
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -40,6 +43,7 @@ func TestCafeNegative(t *testing.T) {
 		{"/cafe", http.StatusBadRequest, "unknown city"},
 		{"/cafe?city=omsk", http.StatusBadRequest, "unknown city"},
 		{"/cafe?city=tula&count=na", http.StatusBadRequest, "incorrect count"},
+		{"/cafe?city=tula&count=-1", http.StatusBadRequest, "incorrect count"},
 	}
 
 	// Итерируемся по всем тестовым запросам
@@ -206,3 +210,259 @@ func TestCafeSearch(t *testing.T) {
 		}
 	}
 }
+
+/*
+TestNewHandlerWithCustomStore:
+Назначение: тестирование newHandler с произвольным Store
+Описание: вместо чтения package-level cafeList использует детерминированный
+in-memory Store, чтобы проверить, что обработчик не завязан на глобальное
+состояние
+*/
+func TestNewHandlerWithCustomStore(t *testing.T) {
+	store := NewMemoryStore(map[string][]Cafe{
+		"springfield": {
+			{Name: "Moe's", City: "springfield", Lat: 1, Lon: 1},
+			{Name: "Kwik-E-Mart Café", City: "springfield", Lat: 2, Lon: 2},
+		},
+	})
+	handler := newHandler(store)
+
+	t.Run("lists cafes from the custom store", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=springfield&count=2", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		res := strings.Split(response.Body.String(), ",")
+		require.Len(t, res, 2)
+	})
+
+	t.Run("unknown city in the custom store is rejected", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow", nil)
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "unknown city", strings.TrimSpace(response.Body.String()))
+	})
+}
+
+/*
+TestCafeOffset:
+Назначение: тестирование пагинации через offset/count
+Описание: проверяет корректность среза по offset, заголовок X-Total-Count
+и наличие нужных rel в заголовке Link
+*/
+func TestCafeOffset(t *testing.T) {
+	handler := http.HandlerFunc(mainHandle)
+
+	t.Run("offset=1&count=2 slices body and sets headers", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow&offset=1&count=2", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		res := strings.Split(response.Body.String(), ",")
+		require.Len(t, res, 2)
+
+		assert.Equal(t, strconv.Itoa(len(cafeList["moscow"])), response.Header().Get("X-Total-Count"))
+
+		link := response.Header().Get("Link")
+		assert.Contains(t, link, `rel="prev"`)
+		assert.Contains(t, link, `rel="next"`)
+		assert.Contains(t, link, `rel="last"`)
+	})
+
+	t.Run("negative offset is rejected", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow&offset=-1", nil)
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "incorrect offset", strings.TrimSpace(response.Body.String()))
+	})
+}
+
+/*
+TestCafeSearchRegex:
+Назначение: тестирование параметра search_mode=regex (и алиаса regex=)
+Описание: проверяет, что поиск по регулярному выражению находит нужные
+кафе и что некомпилируемый паттерн возвращает 400 invalid regex
+*/
+func TestCafeSearchRegex(t *testing.T) {
+	handler := http.HandlerFunc(mainHandle)
+
+	requests := []struct {
+		request   string // URL запроса
+		wantCount int    // ожидаемое количество кафе в ответе
+	}{
+		{"/cafe?city=moscow&search=^Ко(фе|т)&search_mode=regex", 1}, // совпадает только "Кофе и кофе"
+		{"/cafe?city=moscow&search=Вилка&search_mode=regex", 1}, // регистр учитывается: совпадает "Вилка и ложка"
+		{"/cafe?city=moscow&regex=кофе", 2},
+	}
+
+	for _, v := range requests {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", v.request, nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		res := strings.Split(strings.TrimSpace(response.Body.String()), ",")
+		assert.Equal(t, v.wantCount, len(res))
+	}
+}
+
+/*
+TestCafeSearchRegexNegative:
+Назначение: тестирование некорректных регулярных выражений
+Описание: аналогично TestCafeNegative, но для параметра regex
+*/
+func TestCafeSearchRegexNegative(t *testing.T) {
+	handler := http.HandlerFunc(mainHandle)
+
+	requests := []struct {
+		request string
+		status  int
+		message string
+	}{
+		{"/cafe?city=moscow&search=(&search_mode=regex", http.StatusBadRequest, "invalid regex"},
+		{"/cafe?city=moscow&regex=(", http.StatusBadRequest, "invalid regex"},
+	}
+
+	for _, v := range requests {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", v.request, nil)
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, v.status, response.Code)
+		assert.Equal(t, v.message, strings.TrimSpace(response.Body.String()))
+	}
+}
+
+/*
+TestCafeProximity:
+Назначение: тестирование геопространственного поиска по lat/lon/radius_km
+Описание: проверяет попадание в радиус, отсутствие результатов вне радиуса
+и порядок сортировки по расстоянию и по имени
+*/
+func TestCafeProximity(t *testing.T) {
+	handler := http.HandlerFunc(mainHandle)
+
+	t.Run("out of range radius finds nothing", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?lat=55.751999&lon=37.600002&radius_km=0.001&city=tula", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		assert.Empty(t, response.Body.String())
+	})
+
+	t.Run("finds and orders by distance", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		// Центр совпадает с координатами "Мир кофе" — он должен оказаться первым.
+		req := httptest.NewRequest("GET", "/cafe?lat=55.751999&lon=37.600002&radius_km=20&city=moscow", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		res := strings.Split(response.Body.String(), ",")
+		require.NotEmpty(t, res)
+		assert.Equal(t, "Мир кофе", res[0])
+	})
+
+	t.Run("sort=name orders alphabetically", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?lat=55.751999&lon=37.600002&radius_km=20&city=moscow&sort=name", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		res := strings.Split(response.Body.String(), ",")
+		require.True(t, sort.StringsAreSorted(res))
+	})
+
+	t.Run("malformed coordinates are rejected", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?lat=na&lon=37.6", nil)
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "incorrect coordinates", strings.TrimSpace(response.Body.String()))
+	})
+
+	t.Run("negative count is rejected", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?lat=55.751999&lon=37.600002&radius_km=20&city=moscow&count=-1", nil)
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "incorrect count", strings.TrimSpace(response.Body.String()))
+	})
+}
+
+/*
+TestCafeFormats:
+Назначение: тестирование content negotiation для /cafe
+Описание: проверяет, что application/json и application/geo+json отдают
+корректно структурированные ответы, а запрос без Accept/format продолжает
+возвращать имена через запятую
+*/
+func TestCafeFormats(t *testing.T) {
+	handler := http.HandlerFunc(mainHandle)
+
+	t.Run("json via Accept header", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow&count=2", nil)
+		req.Header.Set("Accept", "application/json")
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+		var got []map[string]interface{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &got))
+		require.Len(t, got, 2)
+		for _, item := range got {
+			assert.Contains(t, item, "name")
+			assert.Contains(t, item, "city")
+			assert.Contains(t, item, "address")
+			assert.Contains(t, item, "lat")
+			assert.Contains(t, item, "lon")
+		}
+	})
+
+	t.Run("geojson via format param", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow&count=2&format=geojson", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		assert.Equal(t, "application/geo+json", response.Header().Get("Content-Type"))
+
+		var got struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Type     string `json:"type"`
+				Geometry struct {
+					Type        string    `json:"type"`
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+			} `json:"features"`
+		}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &got))
+		assert.Equal(t, "FeatureCollection", got.Type)
+		require.Len(t, got.Features, 2)
+		for _, f := range got.Features {
+			assert.Equal(t, "Feature", f.Type)
+			assert.Equal(t, "Point", f.Geometry.Type)
+			require.Len(t, f.Geometry.Coordinates, 2)
+		}
+	})
+
+	t.Run("default stays comma-joined names", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe?city=moscow&count=2", nil)
+		handler.ServeHTTP(response, req)
+
+		checkStatus(t, response)
+		assert.Equal(t, 2, len(strings.Split(response.Body.String(), ",")))
+	})
+}
@@ -0,0 +1,169 @@
+// Package middleware содержит сквозные HTTP-обработчики, общие для всего
+// сервиса: логирование, трассировку запросов и т.п.
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// config собирает настройки LoggingHandler, задаваемые через Option.
+type config struct {
+	writer         io.Writer
+	trustedProxies []string
+}
+
+// Option настраивает LoggingHandler.
+type Option func(*config)
+
+// WithWriter направляет лог-строки в w вместо os.Stdout. Полезно в тестах,
+// где нужно перехватить вывод.
+func WithWriter(w io.Writer) Option {
+	return func(c *config) { c.writer = w }
+}
+
+// WithTrustedProxies задаёт список IP и CIDR прокси, которым разрешено
+// подменять клиентский адрес через X-Forwarded-For.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(c *config) { c.trustedProxies = proxies }
+}
+
+// accessLogEntry — одна JSON-строка структурированного лога доступа.
+type accessLogEntry struct {
+	Timestamp  time.Time `json:"ts"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Bytes      int       `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	Remote     string    `json:"remote"`
+	RequestID  string    `json:"request_id"`
+	City       string    `json:"city"`
+	Count      string    `json:"count"`
+	Search     string    `json:"search"`
+}
+
+/*
+LoggingHandler:
+Назначение: middleware структурированного логирования доступа
+Описание: оборачивает next, присваивает/пробрасывает X-Request-ID (генерируя
+UUIDv4, если заголовок отсутствует), определяет реальный IP клиента с учётом
+доверенных прокси из X-Forwarded-For и пишет одну JSON-строку на запрос
+с полями ts, method, path, status, bytes, duration_ms, remote, request_id,
+city, count, search.
+*/
+func LoggingHandler(next http.Handler, opts ...Option) http.Handler {
+	c := &config{writer: os.Stdout}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		requestID := req.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+
+		query := req.URL.Query()
+		entry := accessLogEntry{
+			Timestamp:  start,
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMs: time.Since(start).Milliseconds(),
+			Remote:     clientIP(req, c.trustedProxies),
+			RequestID:  requestID,
+			City:       query.Get("city"),
+			Count:      query.Get("count"),
+			Search:     query.Get("search"),
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(c.writer, string(line))
+	})
+}
+
+// statusRecorder перехватывает код ответа и число записанных байт.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// clientIP возвращает адрес клиента: первый (крайний левый) адрес из
+// X-Forwarded-For, если прямой отправитель запроса входит в trustedProxies,
+// иначе хост из req.RemoteAddr.
+func clientIP(req *http.Request, trustedProxies []string) string {
+	host := req.RemoteAddr
+	if h, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		host = h
+	}
+
+	xff := req.Header.Get("X-Forwarded-For")
+	if xff == "" || !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	parts := strings.Split(xff, ",")
+	return strings.TrimSpace(parts[0])
+}
+
+func isTrustedProxy(host string, trustedProxies []string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, p := range trustedProxies {
+		if _, cidr, err := net.ParseCIDR(p); err == nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if net.ParseIP(p).Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRequestID генерирует случайный UUIDv4 (RFC 4122).
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
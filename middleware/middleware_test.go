@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+}
+
+/*
+TestLoggingHandlerSetsRequestID:
+Назначение: тестирование генерации X-Request-ID
+Описание: проверяет, что при отсутствии заголовка в запросе обработчик
+генерирует и возвращает непустой X-Request-ID
+*/
+func TestLoggingHandlerSetsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	handler := LoggingHandler(okHandler(), WithWriter(&buf))
+
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/cafe?city=moscow", nil)
+	handler.ServeHTTP(response, req)
+
+	assert.NotEmpty(t, response.Header().Get("X-Request-ID"))
+}
+
+/*
+TestLoggingHandlerEchoesRequestID:
+Назначение: тестирование проброса X-Request-ID
+Описание: проверяет, что уже переданный клиентом X-Request-ID возвращается
+без изменений
+*/
+func TestLoggingHandlerEchoesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	handler := LoggingHandler(okHandler(), WithWriter(&buf))
+
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/cafe?city=moscow", nil)
+	req.Header.Set("X-Request-ID", "given-id")
+	handler.ServeHTTP(response, req)
+
+	assert.Equal(t, "given-id", response.Header().Get("X-Request-ID"))
+}
+
+/*
+TestLoggingHandlerLogsExpectedFields:
+Назначение: тестирование содержимого лог-строки
+Описание: проверяет, что строка лога, записанная в переданный io.Writer,
+содержит ожидаемые поля запроса
+*/
+func TestLoggingHandlerLogsExpectedFields(t *testing.T) {
+	var buf bytes.Buffer
+	handler := LoggingHandler(okHandler(), WithWriter(&buf))
+
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/cafe?city=tula&count=2&search=кофе", nil)
+	req.Header.Set("X-Request-ID", "fixed-id")
+	handler.ServeHTTP(response, req)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/cafe", entry["path"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, float64(len("ok")), entry["bytes"])
+	assert.Equal(t, "fixed-id", entry["request_id"])
+	assert.Equal(t, "tula", entry["city"])
+	assert.Equal(t, "2", entry["count"])
+	assert.Equal(t, "кофе", entry["search"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.Contains(t, entry, "remote")
+	assert.Contains(t, entry, "ts")
+}
+
+/*
+TestLoggingHandlerTrustedProxy:
+Назначение: тестирование X-Forwarded-For за доверенным прокси
+Описание: проверяет, что remote берётся из X-Forwarded-For только когда
+RemoteAddr входит в список доверенных прокси
+*/
+func TestLoggingHandlerTrustedProxy(t *testing.T) {
+	t.Run("trusted proxy forwards client IP", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggingHandler(okHandler(), WithWriter(&buf), WithTrustedProxies("192.0.2.1/32"))
+
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe", nil)
+		req.RemoteAddr = "192.0.2.1:54321"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7, 192.0.2.1")
+		handler.ServeHTTP(response, req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "203.0.113.7", entry["remote"])
+	})
+
+	t.Run("untrusted proxy is ignored", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggingHandler(okHandler(), WithWriter(&buf))
+
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/cafe", nil)
+		req.RemoteAddr = "198.51.100.2:54321"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		handler.ServeHTTP(response, req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "198.51.100.2", entry["remote"])
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownCity возвращается Store.List, когда для города нет данных.
+var ErrUnknownCity = errors.New("unknown city")
+
+// Store абстрагирует источник данных о кафе: in-memory карту или файл,
+// загруженный при старте процесса.
+type Store interface {
+	List(city string) ([]Cafe, error)
+	Cities() []string
+}
+
+// memoryStore хранит кафе в карте city -> []Cafe, как и исходный cafeList.
+type memoryStore struct {
+	data map[string][]Cafe
+}
+
+// NewMemoryStore оборачивает готовую карту city -> []Cafe в Store.
+func NewMemoryStore(data map[string][]Cafe) Store {
+	return &memoryStore{data: data}
+}
+
+func (s *memoryStore) List(city string) ([]Cafe, error) {
+	cafes, ok := s.data[city]
+	if !ok {
+		return nil, ErrUnknownCity
+	}
+	return cafes, nil
+}
+
+func (s *memoryStore) Cities() []string {
+	cities := make([]string, 0, len(s.data))
+	for city := range s.data {
+		cities = append(cities, city)
+	}
+	return cities
+}
+
+// NewFileStore загружает кафе из CSV или JSON файла по расширению path
+// (city,name,lat,lon,tags для CSV) и возвращает in-memory Store поверх
+// загруженных данных.
+func NewFileStore(path string) (Store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cafes []Cafe
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		cafes, err = decodeCafesJSON(f)
+	default:
+		cafes, err = decodeCafesCSV(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]Cafe)
+	for _, c := range cafes {
+		data[c.City] = append(data[c.City], c)
+	}
+	return NewMemoryStore(data), nil
+}
+
+// fileCafe — запись формата файла (city,name,lat,lon,tags). Tags сейчас не
+// используются обработчиком, но сохраняются в Cafe.Tags для будущих фильтров.
+type fileCafe struct {
+	City string  `json:"city"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Tags string  `json:"tags"`
+}
+
+func decodeCafesJSON(r io.Reader) ([]Cafe, error) {
+	var raw []fileCafe
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return toCafes(raw), nil
+}
+
+func decodeCafesCSV(r io.Reader) ([]Cafe, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && strings.EqualFold(rows[0][0], "city") {
+		rows = rows[1:] // пропускаем заголовок
+	}
+
+	raw := make([]fileCafe, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 4 {
+			continue
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err != nil {
+			return nil, err
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+		if err != nil {
+			return nil, err
+		}
+		fc := fileCafe{City: strings.TrimSpace(row[0]), Name: strings.TrimSpace(row[1]), Lat: lat, Lon: lon}
+		if len(row) > 4 {
+			fc.Tags = strings.TrimSpace(row[4])
+		}
+		raw = append(raw, fc)
+	}
+	return toCafes(raw), nil
+}
+
+func toCafes(raw []fileCafe) []Cafe {
+	cafes := make([]Cafe, 0, len(raw))
+	for _, fc := range raw {
+		cafes = append(cafes, Cafe{Name: fc.Name, City: fc.City, Lat: fc.Lat, Lon: fc.Lon, Tags: fc.Tags})
+	}
+	return cafes
+}